@@ -1,344 +1,255 @@
 package amazon
 
 import (
-	"encoding/xml"
-	"fmt"
-	"net/http"
-	"strconv"
-
+	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
-	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
-	"net/url"
-	"sort"
+	"net/http"
 	"strings"
 	"time"
 )
 
-// Some of the code in this file was copied from github.com/DDRBoxman/go-amazon-product-api
-// License: https://github.com/DDRBoxman/go-amazon-product-api/blob/master/LICENSE
-
-// Response describes the generic API Response
-// Response describes the generic API Response
-type AWSResponse struct {
-	OperationRequest struct {
-		RequestID             string     `xml:"RequestId"`
-		Arguments             []Argument `xml:"Arguments>Argument"`
-		RequestProcessingTime float64
-	}
-}
-
-// Argument todo
-type Argument struct {
-	Name  string `xml:"Name,attr"`
-	Value string `xml:"Value,attr"`
-}
-
-// Image todo
-type Image struct {
-	URL    string
-	Height uint16
-	Width  uint16
-}
-
-// Price describes the product price as
-// Amount of cents in CurrencyCode
-type Price struct {
-	Amount         uint
-	CurrencyCode   string
-	FormattedPrice string
-}
-
-type TopSeller struct {
-	ASIN  string
-	Title string
-}
+// Product Advertising API 5.0 constants. See
+// https://webservices.amazon.com/paapi5/documentation/ for the full
+// reference.
+const (
+	paapiPath      = "/paapi5/searchitems"
+	paapiTarget    = "com.amazon.paapi5.v1.ProductAdvertisingAPIv1.SearchItems"
+	paapiService   = "ProductAdvertisingAPI"
+	paapiAlgorithm = "AWS4-HMAC-SHA256"
+)
 
-// Item represents a product returned by the API
+// SearchItemsRequest is the JSON body sent to the PA-API 5.0 SearchItems
+// operation.
+type SearchItemsRequest struct {
+	Keywords    string   `json:"Keywords"`
+	SearchIndex string   `json:"SearchIndex"`
+	ItemPage    int      `json:"ItemPage"`
+	PartnerTag  string   `json:"PartnerTag"`
+	PartnerType string   `json:"PartnerType"`
+	Marketplace string   `json:"Marketplace"`
+	Resources   []string `json:"Resources"`
+}
+
+// defaultResources mirrors the ItemAttributes/Images/EditorialReview
+// response groups the site used under ECS 2013-08-01, plus ExternalIds
+// so callers can de-duplicate results against other providers by ISBN
+// instead of falling back to the Amazon-specific ASIN.
+var defaultResources = []string{
+	"ItemInfo.Title",
+	"ItemInfo.ExternalIds",
+	"Images.Primary.Large",
+	"EditorialReviews",
+}
+
+// Item represents a single product returned by the SearchItems operation.
 type Item struct {
-	ASIN             string
-	URL              string
-	DetailPageURL    string
-	ItemAttributes   *ItemAttributes
-	OfferSummary     OfferSummary
-	Offers           Offers
-	SalesRank        int
-	SmallImage       *Image
-	MediumImage      *Image
-	LargeImage       *Image
-	ImageSets        *ImageSets
-	EditorialReviews EditorialReviews
-	BrowseNodes      struct {
-		BrowseNode []BrowseNode
-	}
-}
-
-// BrowseNode represents a browse node returned by API
-type BrowseNode struct {
-	BrowseNodeID string `xml:"BrowseNodeId"`
-	Name         string
-	TopSellers   struct {
-		TopSeller []TopSeller
-	}
-	Ancestors struct {
-		BrowseNode []BrowseNode
-	}
-}
-
-// ItemAttributes response group
-type ItemAttributes struct {
-	Author          string
-	Binding         string
-	Brand           string
-	Color           string
-	EAN             string
-	Creator         string
-	Title           string
-	ListPrice       Price
-	Manufacturer    string
-	Publisher       string
-	NumberOfItems   int
-	PackageQuantity int
-	Feature         string
-	Model           string
-	ProductGroup    string
-	ReleaseDate     string
-	Studio          string
-	Warranty        string
-	Size            string
-	UPC             string
-}
-
-// Offer response attribute
-type Offer struct {
-	Condition       string `xml:"OfferAttributes>Condition"`
-	ID              string `xml:"OfferListing>OfferListingId"`
-	Price           Price  `xml:"OfferListing>Price"`
-	PercentageSaved uint   `xml:"OfferListing>PercentageSaved"`
-	Availability    string `xml:"OfferListing>Availability"`
-}
-
-// Offers response group
-type Offers struct {
-	TotalOffers     int
-	TotalOfferPages int
-	MoreOffersURL   string  `xml:"MoreOffersUrl"`
-	Offers          []Offer `xml:"Offer"`
-}
-
-// OfferSummary response group
-type OfferSummary struct {
-	LowestNewPrice   Price
-	LowerUsedPrice   Price
-	TotalNew         int
-	TotalUsed        int
-	TotalCollectible int
-	TotalRefurbished int
-}
-
-// EditorialReview response attribute
-type EditorialReview struct {
-	Source  string
-	Content string
-}
-
-// EditorialReviews response group
-type EditorialReviews struct {
-	EditorialReview EditorialReview
-}
-
-// BrowseNodeLookupRequest is the confirmation of a BrowseNodeInfo request
-type BrowseNodeLookupRequest struct {
-	BrowseNodeId  string
-	ResponseGroup string
-}
-
-// ItemLookupRequest is the confirmation of a ItemLookup request
-type ItemLookupRequest struct {
-	IDType        string `xml:"IdType"`
-	ItemID        string `xml:"ItemId"`
-	ResponseGroup string `xml:"ResponseGroup"`
-	VariationPage string
-}
-
-// ItemLookupResponse describes the API response for the ItemLookup operation
-type ItemLookupResponse struct {
-	AWSResponse
-	Items struct {
-		Request struct {
-			IsValid           bool
-			ItemLookupRequest ItemLookupRequest
-		}
-		Item Item `xml:"Item"`
-	}
-}
-
-// ItemSearchRequest is the confirmation of a ItemSearch request
-type ItemSearchRequest struct {
-	Keywords      string `xml:"Keywords"`
-	SearchIndex   string `xml:"SearchIndex"`
-	ResponseGroup string `xml:"ResponseGroup"`
-}
-
-type ItemSearchResponse struct {
-	AWSResponse
-	Items struct {
-		Request struct {
-			IsValid           bool
-			ItemSearchRequest ItemSearchRequest
-		}
-		Items                []Item `xml:"Item"`
-		TotalResult          int
-		TotalPages           int
-		MoreSearchResultsUrl string
-	}
-}
-
-type BrowseNodeLookupResponse struct {
-	AWSResponse
-	BrowseNodes struct {
-		Request struct {
-			IsValid                 bool
-			BrowseNodeLookupRequest BrowseNodeLookupRequest
-		}
-		BrowseNode BrowseNode
-	}
-}
-
-type ImageSets struct {
-	ImageSet []ImageSet
-}
-
-type ImageSet struct {
-	//Category string `xml:"Category,attr"`
-	Category       string `xml:",attr"`
-	SwatchImage    *Image
-	SmallImage     *Image
-	ThumbnailImage *Image
-	TinyImage      *Image
-	MediumImage    *Image
-	LargeImage     *Image
-}
-
+	ASIN          string `json:"ASIN"`
+	DetailPageURL string `json:"DetailPageURL"`
+	ItemInfo      struct {
+		Title struct {
+			DisplayValue string `json:"DisplayValue"`
+		} `json:"Title"`
+		ExternalIds struct {
+			ISBNs struct {
+				DisplayValues []string `json:"DisplayValues"`
+			} `json:"ISBNs"`
+		} `json:"ExternalIds"`
+	} `json:"ItemInfo"`
+	Images struct {
+		Primary struct {
+			Large struct {
+				URL    string `json:"URL"`
+				Height int    `json:"Height"`
+				Width  int    `json:"Width"`
+			} `json:"Large"`
+		} `json:"Primary"`
+	} `json:"Images"`
+}
+
+// SearchItemsResponse is the JSON body returned by the PA-API 5.0
+// SearchItems operation.
+type SearchItemsResponse struct {
+	SearchResult struct {
+		Items            []Item `json:"Items"`
+		TotalResultCount int    `json:"TotalResultCount"`
+	} `json:"SearchResult"`
+	Errors []struct {
+		Code    string `json:"Code"`
+		Message string `json:"Message"`
+	} `json:"Errors"`
+}
+
+// Doer is satisfied by *http.Client and lets tests substitute a mock HTTP
+// client for AmazonProductAPI without making real network calls.
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// AmazonProductAPI is a client for the Amazon Product Advertising API 5.0.
+// Region and Marketplace together select which Amazon storefront is
+// queried, e.g. Region "us-east-1" / Marketplace "www.amazon.com" or
+// Region "eu-west-1" / Marketplace "www.amazon.co.uk".
 type AmazonProductAPI struct {
 	AccessKey    string
 	SecretKey    string
 	AssociateTag string
 	Host         string
-	Client       *http.Client
+	Region       string
+	Marketplace  string
+	Client       Doer
 }
 
-func (api AmazonProductAPI) genSignAndFetch(Operation string, Parameters map[string]string) (string, error) {
-	genURL, err := generateAmazonURL(api, Operation, Parameters)
+func (api AmazonProductAPI) genSignAndFetch(ctx context.Context, req SearchItemsRequest) (SearchItemsResponse, error) {
+	var isr SearchItemsResponse
+
+	body, err := json.Marshal(req)
 	if err != nil {
-		return "", err
+		return isr, err
 	}
 
-	setTimestamp(genURL)
-
-	signedurl, err := signAmazonURL(genURL, api)
+	httpReq, err := newSignedRequest(ctx, api, body, time.Now().UTC())
 	if err != nil {
-		return "", err
+		return isr, err
 	}
 
-	if api.Client == nil {
-		api.Client = http.DefaultClient
+	client := api.Client
+	if client == nil {
+		client = http.DefaultClient
 	}
 
-	resp, err := api.Client.Get(signedurl)
+	resp, err := client.Do(httpReq)
 	if err != nil {
-		return "", err
+		return isr, err
 	}
-
 	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+
+	respBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return isr, err
 	}
 
-	return string(body), nil
-}
-
-// Search for Products on the Amazon API. Index should be a valid Amazon product category,
-// e.g. "Books".
-func (api AmazonProductAPI) Search(index string, keywords string, page int) (isr ItemSearchResponse, err error) {
-	params := map[string]string{
-		"Keywords":      url.QueryEscape(keywords),
-		"ResponseGroup": "Images,ItemAttributes,Small,EditorialReview",
-		"ItemPage":      strconv.FormatInt(int64(page), 10),
-		"SearchIndex":   index,
+	if resp.StatusCode != http.StatusOK {
+		return isr, fmt.Errorf("amazon: PA-API returned %s: %s", resp.Status, respBody)
 	}
-	result, err := api.genSignAndFetch("ItemSearch", params)
-	if err != nil {
-		return
+
+	if err := json.Unmarshal(respBody, &isr); err != nil {
+		return isr, err
 	}
 
-	err = xml.Unmarshal([]byte(result), &isr)
-	if err != nil {
-		return
+	if len(isr.SearchResult.Items) == 0 && len(isr.Errors) > 0 {
+		e := isr.Errors[0]
+		return isr, fmt.Errorf("amazon: PA-API error %s: %s", e.Code, e.Message)
 	}
-	return isr, err
+
+	return isr, nil
 }
 
-func generateAmazonURL(api AmazonProductAPI, Operation string, Parameters map[string]string) (finalURL *url.URL, err error) {
+// Search for Products on the Amazon API. Index should be a valid Amazon
+// product category, e.g. "Books". ctx governs the lifetime of the
+// underlying HTTP request.
+func (api AmazonProductAPI) Search(ctx context.Context, index string, keywords string, page int) (SearchItemsResponse, error) {
+	return api.genSignAndFetch(ctx, SearchItemsRequest{
+		Keywords:    keywords,
+		SearchIndex: index,
+		ItemPage:    page,
+		PartnerTag:  api.AssociateTag,
+		PartnerType: "Associates",
+		Marketplace: api.Marketplace,
+		Resources:   defaultResources,
+	})
+}
 
-	result, err := url.Parse(api.Host)
-	if err != nil {
-		return nil, err
+// newSignedRequest builds the PA-API 5.0 HTTP request for body, signed
+// with AWS Signature Version 4.
+func newSignedRequest(ctx context.Context, api AmazonProductAPI, body []byte, now time.Time) (*http.Request, error) {
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+
+	headers := map[string]string{
+		"content-encoding": "amz-1.0",
+		"content-type":     "application/json; charset=utf-8",
+		"host":             api.Host,
+		"x-amz-date":       amzDate,
+		"x-amz-target":     paapiTarget,
 	}
+	signedHeaders := "content-encoding;content-type;host;x-amz-date;x-amz-target"
 
-	result.Host = api.Host
-	result.Scheme = "http"
-	result.Path = "/onca/xml"
+	canonicalRequest := strings.Join([]string{
+		http.MethodPost,
+		paapiPath,
+		"", // no query string
+		canonicalHeaders(headers, signedHeaders),
+		signedHeaders,
+		hashHex(body),
+	}, "\n")
 
-	values := url.Values{}
-	values.Add("Operation", Operation)
-	values.Add("Service", "AWSECommerceService")
-	values.Add("AWSAccessKeyId", api.AccessKey)
-	values.Add("Version", "2013-08-01")
-	values.Add("AssociateTag", api.AssociateTag)
+	credentialScope := fmt.Sprintf("%s/%s/%s/aws4_request", dateStamp, api.Region, paapiService)
+	stringToSign := strings.Join([]string{
+		paapiAlgorithm,
+		amzDate,
+		credentialScope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
 
-	for k, v := range Parameters {
-		values.Set(k, v)
-	}
+	key := signingKey(api.SecretKey, dateStamp, api.Region, paapiService)
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
 
-	params := values.Encode()
-	result.RawQuery = params
-
-	return result, nil
-}
+	authorization := fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		paapiAlgorithm, api.AccessKey, credentialScope, signedHeaders, signature)
 
-func setTimestamp(origURL *url.URL) (err error) {
-	values, err := url.ParseQuery(origURL.RawQuery)
+	url := "https://" + api.Host + paapiPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for k, v := range headers {
+		if k == "host" {
+			continue
+		}
+		req.Header.Set(k, v)
 	}
-	values.Set("Timestamp", time.Now().UTC().Format(time.RFC3339))
-	origURL.RawQuery = values.Encode()
+	req.Header.Set("Authorization", authorization)
+	req.ContentLength = int64(len(body))
 
-	return nil
+	return req, nil
 }
 
-func signAmazonURL(origURL *url.URL, api AmazonProductAPI) (signedURL string, err error) {
-	escapeURL := strings.Replace(origURL.RawQuery, ",", "%2C", -1)
-	escapeURL = strings.Replace(escapeURL, ":", "%3A", -1)
-
-	params := strings.Split(escapeURL, "&")
-	sort.Strings(params)
-	sortedParams := strings.Join(params, "&")
+// canonicalHeaders renders the lower-cased "name:value\n" header block
+// required by the SigV4 canonical request, in the order given by
+// signedHeaders.
+func canonicalHeaders(headers map[string]string, signedHeaders string) string {
+	var buf bytes.Buffer
+	for _, name := range strings.Split(signedHeaders, ";") {
+		buf.WriteString(name)
+		buf.WriteString(":")
+		buf.WriteString(headers[name])
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
 
-	toSign := fmt.Sprintf("GET\n%s\n%s\n%s", origURL.Host, origURL.Path, sortedParams)
+// signingKey derives the SigV4 signing key via successive HMAC-SHA256 of
+// "AWS4" + secret, the date, the region, the service, and "aws4_request".
+func signingKey(secret, dateStamp, region, service string) []byte {
+	kDate := hmacSHA256([]byte("AWS4"+secret), dateStamp)
+	kRegion := hmacSHA256(kDate, region)
+	kService := hmacSHA256(kRegion, service)
+	return hmacSHA256(kService, "aws4_request")
+}
 
-	hasher := hmac.New(sha256.New, []byte(api.SecretKey))
-	_, err = hasher.Write([]byte(toSign))
-	if err != nil {
-		return "", err
-	}
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
 
-	hash := base64.StdEncoding.EncodeToString(hasher.Sum(nil))
-	hash = url.QueryEscape(hash)
-	newParams := fmt.Sprintf("%s&Signature=%s", sortedParams, hash)
-	origURL.RawQuery = newParams
-	return origURL.String(), nil
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
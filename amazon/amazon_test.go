@@ -0,0 +1,80 @@
+package amazon_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/productiongo/bestbooktolearn/amazon"
+	"github.com/productiongo/bestbooktolearn/amazon/mock"
+)
+
+func testAPI(client amazon.Doer) amazon.AmazonProductAPI {
+	return amazon.AmazonProductAPI{
+		AccessKey:    "AKIDEXAMPLE",
+		SecretKey:    "secret",
+		AssociateTag: "bbtl-20",
+		Host:         "webservices.amazon.com",
+		Region:       "us-east-1",
+		Marketplace:  "www.amazon.com",
+		Client:       client,
+	}
+}
+
+func TestSearch(t *testing.T) {
+	cases := []struct {
+		name      string
+		client    *mock.Client
+		wantErr   bool
+		wantItems int
+	}{
+		{
+			name:      "successful multi-item response",
+			client:    &mock.Client{},
+			wantItems: 2,
+		},
+		{
+			name:      "empty results",
+			client:    &mock.Client{FixtureName: "empty"},
+			wantItems: 0,
+		},
+		{
+			name: "503 with Retry-After",
+			client: &mock.Client{
+				StatusCode: http.StatusServiceUnavailable,
+				Header:     http.Header{"Retry-After": []string{"5"}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "malformed body",
+			client:  &mock.Client{FixtureName: "malformed"},
+			wantErr: true,
+		},
+		{
+			name:    "200 OK with throttling Errors and no Items",
+			client:  &mock.Client{FixtureName: "throttled"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			api := testAPI(tc.client)
+
+			resp, err := api.Search(context.Background(), "Books", "go programming", 1)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Search() error = nil, want non-nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Search() error = %v, want nil", err)
+			}
+			if got := len(resp.SearchResult.Items); got != tc.wantItems {
+				t.Errorf("Search() returned %d items, want %d", got, tc.wantItems)
+			}
+		})
+	}
+}
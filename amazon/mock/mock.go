@@ -0,0 +1,61 @@
+// Package mock implements amazon.Doer with canned fixture responses, so
+// tests can exercise AmazonProductAPI.Search without a network call.
+package mock
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+//go:embed fixtures
+var Data embed.FS
+
+// Client is a mock amazon.Doer that serves a canned fixture for every
+// request. By default the fixture is chosen from the request's host and
+// X-Amz-Target header, mirroring the real PA-API endpoint/operation
+// pair; set FixtureName to serve a specific fixture regardless of the
+// request, e.g. to exercise an empty-results or malformed-body case.
+type Client struct {
+	// FixtureName, if set, names the file (without extension) under
+	// fixtures/ to serve, overriding the default host+operation lookup.
+	FixtureName string
+	// StatusCode, if non-zero, overrides the default 200 OK status.
+	StatusCode int
+	// Header is merged into every response's headers, e.g. to set
+	// Retry-After alongside a 503 StatusCode.
+	Header http.Header
+}
+
+// Do implements amazon.Doer.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	name := c.FixtureName
+	if name == "" {
+		name = req.URL.Host + "_" + req.Header.Get("X-Amz-Target")
+	}
+
+	body, err := Data.ReadFile("fixtures/" + name + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("mock: no fixture %q: %w", name, err)
+	}
+
+	status := c.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	for k, v := range c.Header {
+		header[k] = v
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Header:     header,
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
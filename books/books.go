@@ -1,18 +1,14 @@
 package books
 
 import (
-	"github.com/productiongo/bestbooktolearn/amazon"
-)
-
-type API struct {
-	amazon amazon.AmazonProductAPI
-}
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
-func New(amz amazon.AmazonProductAPI) *API {
-	return &API{
-		amazon: amz,
-	}
-}
+	"golang.org/x/sync/singleflight"
+)
 
 type BookImage struct {
 	URL    string
@@ -27,29 +23,191 @@ type Book struct {
 	LargeImage *BookImage
 }
 
-func (api API) Search(keywords string, page int) (books []Book, err error) {
-	r, err := api.amazon.Search("Books", keywords, page)
-	if err != nil {
-		return
+// Provider is implemented by anything that can search a book-data backend
+// (Amazon, Google Books, OpenLibrary, ...) for books matching a set of
+// keywords.
+type Provider interface {
+	Search(ctx context.Context, keywords string, page int) ([]Book, error)
+}
+
+// Factory builds a Provider from its configuration, typically API keys
+// and endpoints pulled from the environment by main.go.
+type Factory func(cfg map[string]string) (Provider, error)
+
+var factories = map[string]Factory{}
+
+// Register makes a named Provider factory available to NewRegistry. It is
+// typically called from a provider package's init function so that
+// enabling a provider is just a matter of blank-importing its package.
+func Register(name string, factory Factory) {
+	factories[name] = factory
+}
+
+// Registry fans a search out to a fixed set of enabled Provider instances
+// and merges the results.
+type Registry struct {
+	names     []string
+	providers map[string]Provider
+	cache     Cache
+	cacheTTL  time.Duration
+	sf        singleflight.Group
+}
+
+// RegistryOption configures optional Registry behavior.
+type RegistryOption func(*Registry)
+
+// WithCache enables caching of each provider's results for ttl. Cache
+// keys are namespaced by provider name, so a single Cache instance can
+// safely be shared across registries and providers.
+func WithCache(cache Cache, ttl time.Duration) RegistryOption {
+	return func(r *Registry) {
+		r.cache = cache
+		r.cacheTTL = ttl
+	}
+}
+
+// NewRegistry builds a Registry containing a Provider for every name in
+// names, configuring each from cfgs[name]. Every name must have already
+// been registered via Register, typically by blank-importing its provider
+// package.
+func NewRegistry(names []string, cfgs map[string]map[string]string, opts ...RegistryOption) (*Registry, error) {
+	providers := make(map[string]Provider, len(names))
+	for _, name := range names {
+		factory, ok := factories[name]
+		if !ok {
+			return nil, fmt.Errorf("books: unknown provider %q", name)
+		}
+		p, err := factory(cfgs[name])
+		if err != nil {
+			return nil, fmt.Errorf("books: configuring provider %q: %w", name, err)
+		}
+		providers[name] = p
 	}
 
-	books = []Book{}
-	for _, item := range r.Items.Items {
-		var img *BookImage
-		if item.LargeImage != nil {
-			img = &BookImage{
-				URL:    item.LargeImage.URL,
-				Width:  int(item.LargeImage.Width),
-				Height: int(item.LargeImage.Height),
+	r := &Registry{names: names, providers: providers, cacheTTL: DefaultCacheTTL}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// CacheStats reports the configured Cache's hit/miss/size counters, or
+// the zero value if no Cache is configured.
+func (r *Registry) CacheStats() CacheStats {
+	if r.cache == nil {
+		return CacheStats{}
+	}
+	return r.cache.Stats()
+}
+
+// cacheKey namespaces a cache entry by provider, keywords and page so
+// results from different providers are never mixed together.
+func cacheKey(provider, keywords string, page int) string {
+	return provider + "\x00" + keywords + "\x00" + strconv.Itoa(page)
+}
+
+// fetch runs provider p's search for keywords/page, consulting and
+// populating the Registry's Cache if one is configured. Concurrent calls
+// for the same provider/keywords/page are collapsed into a single
+// upstream call via singleflight.
+func (r *Registry) fetch(ctx context.Context, name string, p Provider, keywords string, page int) ([]Book, error) {
+	key := cacheKey(name, keywords, page)
+
+	v, err, _ := r.sf.Do(key, func() (interface{}, error) {
+		if r.cache != nil {
+			if cached, ok := r.cache.Get(key); ok {
+				return cached, nil
 			}
 		}
-		b := Book{
-			Title:      item.ItemAttributes.Title,
-			ISBN:       item.ItemAttributes.EAN,
-			URL:        item.DetailPageURL,
-			LargeImage: img,
+
+		found, err := p.Search(ctx, keywords, page)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.cache != nil {
+			r.cache.Set(key, found, r.cacheTTL)
+		}
+		return found, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]Book), nil
+}
+
+// Search queries every enabled provider concurrently and merges the
+// results, de-duplicating by ISBN. When two providers return a book with
+// the same ISBN, the result from whichever provider sorts earliest in
+// names wins.
+func (r *Registry) Search(ctx context.Context, keywords string, page int) ([]Book, error) {
+	type result struct {
+		name  string
+		books []Book
+		err   error
+	}
+
+	results := make(chan result, len(r.names))
+	for _, name := range r.names {
+		go func(name string, p Provider) {
+			books, err := r.fetch(ctx, name, p, keywords, page)
+			results <- result{name: name, books: books, err: err}
+		}(name, r.providers[name])
+	}
+
+	byName := make(map[string]result, len(r.names))
+	for i := 0; i < len(r.names); i++ {
+		select {
+		case res := <-results:
+			byName[res.name] = res
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	seen := map[string]bool{}
+	merged := []Book{}
+	var errs []string
+	for _, name := range r.names {
+		res := byName[name]
+		if res.err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, res.err))
+			continue
+		}
+		for _, b := range res.books {
+			if b.ISBN != "" && seen[b.ISBN] {
+				continue
+			}
+			if b.ISBN != "" {
+				seen[b.ISBN] = true
+			}
+			merged = append(merged, b)
 		}
-		books = append(books, b)
 	}
-	return books, nil
+
+	if len(merged) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("books: all providers failed: %s", strings.Join(errs, "; "))
+	}
+	return merged, nil
+}
+
+// API is the entry point used by the site handlers to search for books
+// across all enabled providers.
+type API struct {
+	registry *Registry
+}
+
+// New returns an API backed by the given Registry.
+func New(registry *Registry) *API {
+	return &API{registry: registry}
+}
+
+func (api *API) Search(ctx context.Context, keywords string, page int) ([]Book, error) {
+	return api.registry.Search(ctx, keywords, page)
+}
+
+// CacheStats reports the registry's Cache hit/miss/size counters, for the
+// /debug/cache endpoint.
+func (api *API) CacheStats() CacheStats {
+	return api.registry.CacheStats()
 }
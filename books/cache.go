@@ -0,0 +1,25 @@
+package books
+
+import "time"
+
+// DefaultCacheTTL is used by Registry when no other TTL is configured via
+// WithCache.
+const DefaultCacheTTL = 24 * time.Hour
+
+// CacheStats reports basic cache effectiveness counters, exposed via the
+// site's /debug/cache endpoint.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+	Size   int
+}
+
+// Cache stores Search results for a bounded time, keyed by an opaque
+// string. Registry's cache keys always include the provider name, so a
+// single Cache instance can be shared across providers without their
+// results cross-contaminating.
+type Cache interface {
+	Get(key string) ([]Book, bool)
+	Set(key string, books []Book, ttl time.Duration)
+	Stats() CacheStats
+}
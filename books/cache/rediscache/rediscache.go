@@ -0,0 +1,86 @@
+// Package rediscache implements books.Cache on top of Redis, so cached
+// search results survive restarts and can be shared across instances.
+package rediscache
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/productiongo/bestbooktolearn/books"
+)
+
+// Cache is a books.Cache backed by a Redis instance. Keys are stored
+// under keyPrefix so they don't collide with unrelated data in a shared
+// Redis instance.
+type Cache struct {
+	client    *redis.Client
+	keyPrefix string
+
+	hits, misses int64
+}
+
+// New returns a Cache that stores entries in client under keyPrefix.
+func New(client *redis.Client, keyPrefix string) *Cache {
+	return &Cache{client: client, keyPrefix: keyPrefix}
+}
+
+// books.Cache has no context parameter, so Redis operations use
+// context.Background(): cache reads/writes are best-effort side effects
+// of a search, not something a caller's request deadline should abort.
+func (c *Cache) Get(key string) ([]books.Book, bool) {
+	data, err := c.client.Get(context.Background(), c.keyPrefix+key).Bytes()
+	if err != nil {
+		atomic.AddInt64(&c.misses, 1)
+		return nil, false
+	}
+
+	var found []books.Book
+	if err := json.Unmarshal(data, &found); err != nil {
+		atomic.AddInt64(&c.misses, 1)
+		return nil, false
+	}
+
+	atomic.AddInt64(&c.hits, 1)
+	return found, true
+}
+
+func (c *Cache) Set(key string, found []books.Book, ttl time.Duration) {
+	data, err := json.Marshal(found)
+	if err != nil {
+		return
+	}
+	c.client.Set(context.Background(), c.keyPrefix+key, data, ttl)
+}
+
+func (c *Cache) Stats() books.CacheStats {
+	return books.CacheStats{
+		Hits:   atomic.LoadInt64(&c.hits),
+		Misses: atomic.LoadInt64(&c.misses),
+		Size:   c.size(),
+	}
+}
+
+// size counts the keys under keyPrefix via SCAN, rather than DBSIZE,
+// since DBSIZE reports every key in the selected database, including
+// unrelated data sharing this Redis instance.
+func (c *Cache) size() int {
+	ctx := context.Background()
+	var count int
+	var cursor uint64
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, c.keyPrefix+"*", 100).Result()
+		if err != nil {
+			return count
+		}
+		count += len(keys)
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	return count
+}
@@ -0,0 +1,96 @@
+// Package ttlru implements books.Cache as a bounded, in-process LRU with
+// per-entry TTLs, for operators who don't want a Redis dependency.
+package ttlru
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/productiongo/bestbooktolearn/books"
+)
+
+type entry struct {
+	key       string
+	books     []books.Book
+	expiresAt time.Time
+}
+
+// Cache is a fixed-capacity, in-process books.Cache. Entries past their
+// TTL are treated as misses and evicted lazily on access; once full, the
+// least recently used entry is evicted to make room for a new one.
+type Cache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+
+	hits, misses int64
+}
+
+// New returns an empty Cache holding at most capacity entries.
+func New(capacity int) *Cache {
+	return &Cache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+func (c *Cache) Get(key string) ([]books.Book, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+	if time.Now().After(e.expiresAt) {
+		c.removeElement(el)
+		c.misses++
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+	c.hits++
+	return e.books, true
+}
+
+func (c *Cache) Set(key string, found []books.Book, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*entry).books = found
+		el.Value.(*entry).expiresAt = expiresAt
+		return
+	}
+
+	el := c.ll.PushFront(&entry{key: key, books: found, expiresAt: expiresAt})
+	c.items[key] = el
+
+	if c.ll.Len() > c.capacity {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *Cache) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*entry).key)
+}
+
+func (c *Cache) Stats() books.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return books.CacheStats{
+		Hits:   c.hits,
+		Misses: c.misses,
+		Size:   c.ll.Len(),
+	}
+}
@@ -0,0 +1,116 @@
+// Package googlebooks implements a books.Provider backed by the Google
+// Books API, for operators who don't have an Amazon Associates account.
+package googlebooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/productiongo/bestbooktolearn/books"
+)
+
+const searchURL = "https://www.googleapis.com/books/v1/volumes"
+
+const pageSize = 10
+
+func init() {
+	books.Register("googlebooks", newProvider)
+}
+
+// provider searches Google Books. An APIKey is optional; Google Books
+// allows a limited number of unauthenticated requests per day.
+type provider struct {
+	apiKey string
+	client *http.Client
+}
+
+func newProvider(cfg map[string]string) (books.Provider, error) {
+	return &provider{apiKey: cfg["APIKey"], client: http.DefaultClient}, nil
+}
+
+func (p *provider) Search(ctx context.Context, keywords string, page int) ([]books.Book, error) {
+	values := url.Values{}
+	values.Set("q", keywords)
+	values.Set("startIndex", strconv.Itoa((page-1)*pageSize))
+	values.Set("maxResults", strconv.Itoa(pageSize))
+	if p.apiKey != "" {
+		values.Set("key", p.apiKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL+"?"+values.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("googlebooks: unexpected status %s", resp.Status)
+	}
+
+	var result volumesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	found := []books.Book{}
+	for _, v := range result.Items {
+		var img *books.BookImage
+		if v.VolumeInfo.ImageLinks.Large != "" {
+			img = &books.BookImage{URL: v.VolumeInfo.ImageLinks.Large}
+		} else if v.VolumeInfo.ImageLinks.Thumbnail != "" {
+			img = &books.BookImage{URL: v.VolumeInfo.ImageLinks.Thumbnail}
+		}
+
+		found = append(found, books.Book{
+			Title:      v.VolumeInfo.Title,
+			ISBN:       isbn(v.VolumeInfo.IndustryIdentifiers),
+			URL:        v.VolumeInfo.InfoLink,
+			LargeImage: img,
+		})
+	}
+	return found, nil
+}
+
+func isbn(ids []industryIdentifier) string {
+	for _, id := range ids {
+		if id.Type == "ISBN_13" {
+			return id.Identifier
+		}
+	}
+	for _, id := range ids {
+		if id.Type == "ISBN_10" {
+			return id.Identifier
+		}
+	}
+	return ""
+}
+
+type volumesResponse struct {
+	Items []volume `json:"items"`
+}
+
+type volume struct {
+	VolumeInfo struct {
+		Title               string               `json:"title"`
+		InfoLink            string               `json:"infoLink"`
+		IndustryIdentifiers []industryIdentifier `json:"industryIdentifiers"`
+		ImageLinks          struct {
+			Thumbnail string `json:"thumbnail"`
+			Large     string `json:"large"`
+		} `json:"imageLinks"`
+	} `json:"volumeInfo"`
+}
+
+type industryIdentifier struct {
+	Type       string `json:"type"`
+	Identifier string `json:"identifier"`
+}
@@ -0,0 +1,87 @@
+// Package openlibrary implements a books.Provider backed by the
+// OpenLibrary search API, for operators who don't have an Amazon
+// Associates account.
+package openlibrary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/productiongo/bestbooktolearn/books"
+)
+
+const searchURL = "https://openlibrary.org/search.json"
+
+func init() {
+	books.Register("openlibrary", newProvider)
+}
+
+type provider struct {
+	client *http.Client
+}
+
+func newProvider(cfg map[string]string) (books.Provider, error) {
+	return &provider{client: http.DefaultClient}, nil
+}
+
+func (p *provider) Search(ctx context.Context, keywords string, page int) ([]books.Book, error) {
+	values := url.Values{}
+	values.Set("q", keywords)
+	values.Set("page", strconv.Itoa(page))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL+"?"+values.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("openlibrary: unexpected status %s", resp.Status)
+	}
+
+	var result searchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	found := []books.Book{}
+	for _, doc := range result.Docs {
+		var img *books.BookImage
+		if doc.CoverID != 0 {
+			img = &books.BookImage{URL: fmt.Sprintf("https://covers.openlibrary.org/b/id/%d-L.jpg", doc.CoverID)}
+		}
+
+		var isbn string
+		if len(doc.ISBN) > 0 {
+			isbn = doc.ISBN[0]
+		}
+
+		found = append(found, books.Book{
+			Title:      doc.Title,
+			ISBN:       isbn,
+			URL:        "https://openlibrary.org" + doc.Key,
+			LargeImage: img,
+		})
+	}
+	return found, nil
+}
+
+type searchResponse struct {
+	Docs []doc `json:"docs"`
+}
+
+type doc struct {
+	Title   string   `json:"title"`
+	Key     string   `json:"key"`
+	ISBN    []string `json:"isbn"`
+	CoverID int      `json:"cover_i"`
+}
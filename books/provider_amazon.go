@@ -0,0 +1,86 @@
+package books
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/productiongo/bestbooktolearn/amazon"
+)
+
+func init() {
+	Register("amazon", newAmazonProvider)
+}
+
+// amazonProvider adapts amazon.AmazonProductAPI to the Provider interface,
+// converting Amazon's product-search response into Book values.
+type amazonProvider struct {
+	api amazon.AmazonProductAPI
+}
+
+func newAmazonProvider(cfg map[string]string) (Provider, error) {
+	accessKey := cfg["AccessKey"]
+	secretKey := cfg["SecretKey"]
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("amazon provider requires AccessKey and SecretKey")
+	}
+	associateTag := cfg["AssociateTag"]
+	if associateTag == "" {
+		return nil, fmt.Errorf("amazon provider requires AssociateTag")
+	}
+	host := cfg["Host"]
+	if host == "" {
+		host = "webservices.amazon.com"
+	}
+	region := cfg["Region"]
+	if region == "" {
+		region = "us-east-1"
+	}
+	marketplace := cfg["Marketplace"]
+	if marketplace == "" {
+		marketplace = "www.amazon.com"
+	}
+
+	return &amazonProvider{api: amazon.AmazonProductAPI{
+		AccessKey:    accessKey,
+		SecretKey:    secretKey,
+		AssociateTag: associateTag,
+		Host:         host,
+		Region:       region,
+		Marketplace:  marketplace,
+	}}, nil
+}
+
+func (p *amazonProvider) Search(ctx context.Context, keywords string, page int) ([]Book, error) {
+	r, err := p.api.Search(ctx, "Books", keywords, page)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Book{}
+	for _, item := range r.SearchResult.Items {
+		var img *BookImage
+		if item.Images.Primary.Large.URL != "" {
+			img = &BookImage{
+				URL:    item.Images.Primary.Large.URL,
+				Width:  item.Images.Primary.Large.Width,
+				Height: item.Images.Primary.Large.Height,
+			}
+		}
+		var isbn string
+		if len(item.ItemInfo.ExternalIds.ISBNs.DisplayValues) > 0 {
+			isbn = item.ItemInfo.ExternalIds.ISBNs.DisplayValues[0]
+		}
+		results = append(results, Book{
+			// isbn is left empty when PA-API doesn't return one for this
+			// item (e.g. some non-book editions); Registry.Search only
+			// de-duplicates on a non-empty ISBN, so this item simply
+			// won't merge with other providers' results for the same
+			// book rather than colliding under an Amazon-specific ASIN.
+			Title:      item.ItemInfo.Title.DisplayValue,
+			ISBN:       isbn,
+			URL:        item.DetailPageURL,
+			LargeImage: img,
+		})
+	}
+	return results, nil
+}
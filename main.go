@@ -2,19 +2,35 @@ package main
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
 	"html/template"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/productiongo/bestbooktolearn/amazon"
+	"github.com/redis/go-redis/v9"
+
 	"github.com/productiongo/bestbooktolearn/books"
+	"github.com/productiongo/bestbooktolearn/books/cache/rediscache"
+	"github.com/productiongo/bestbooktolearn/books/cache/ttlru"
+	_ "github.com/productiongo/bestbooktolearn/books/googlebooks"
+	_ "github.com/productiongo/bestbooktolearn/books/openlibrary"
+	"github.com/productiongo/bestbooktolearn/nominations"
+	"github.com/productiongo/bestbooktolearn/nominations/memory"
+	"github.com/productiongo/bestbooktolearn/nominations/sqlite"
 )
 
 // Site implements the methods we need to run a BestBookToLearn
@@ -24,12 +40,20 @@ type Site struct {
 	Handler         *http.ServeMux
 	GracefulTimeout time.Duration
 
+	// ProviderTimeout bounds how long a single request will wait on
+	// book-provider searches before giving up, independent of the
+	// server's WriteTimeout.
+	ProviderTimeout time.Duration
+
 	templateDir string
 	staticDir   string
 
-	topics    []string
-	bookAPI   *books.API
-	templates map[string]*template.Template
+	topics          []string
+	bookAPI         *books.API
+	templates       map[string]*template.Template
+	nominationStore nominations.Store
+	csrfSecret      []byte
+	nominateLimiter *ipRateLimiter
 }
 
 // PageTemplates is a slice containing the known HTML templates
@@ -45,13 +69,22 @@ var PageTemplates = []string{
 // NewSite returns a new Site with a multiplexer for handling
 // requests. It also pregenerates routes for the given
 // topics.
-func NewSite(topics []string, bookAPI *books.API, templateDir, staticDir string) (*Site, error) {
+func NewSite(topics []string, bookAPI *books.API, nominationStore nominations.Store, templateDir, staticDir string) (*Site, error) {
+	csrfSecret := make([]byte, 32)
+	if _, err := rand.Read(csrfSecret); err != nil {
+		return nil, err
+	}
+
 	s := &Site{
 		GracefulTimeout: 5 * time.Second,
+		ProviderTimeout: 3 * time.Second,
 		templateDir:     templateDir,
 		staticDir:       staticDir,
 		topics:          topics,
 		bookAPI:         bookAPI,
+		nominationStore: nominationStore,
+		csrfSecret:      csrfSecret,
+		nominateLimiter: newIPRateLimiter(10 * time.Second),
 	}
 
 	// load in HTML templates
@@ -71,6 +104,7 @@ func (s *Site) initHandlers(topics []string) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", s.HomeHandler)
 	mux.HandleFunc("/about", s.AboutHandler)
+	mux.HandleFunc("/debug/cache", s.DebugCacheHandler)
 	s.Handler = mux
 
 	// initialize static file server
@@ -175,58 +209,320 @@ func (s Site) TopicHandler(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.EscapedPath()
 	slug := strings.Trim(path, "/")
 	topic := strings.Title(strings.Replace(slug, "-", " ", -1))
-	b, err := s.bookAPI.Search(topic, 1)
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.ProviderTimeout)
+	defer cancel()
+	b, err := s.bookAPI.Search(ctx, topic, 1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	noms, err := s.nominationStore.List(slug)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	data := map[string]interface{}{
-		"slug":  slug,
-		"topic": topic,
-		"books": b,
+		"slug":        slug,
+		"topic":       topic,
+		"books":       b,
+		"nominations": noms,
 	}
 	s.render(w, "topic", data)
 }
 
 // NominateHandler handles a request for the page where users can
-// nominate a book for a particular category.
+// nominate a book for a particular category, and the POST of that
+// nomination form.
 func (s Site) NominateHandler(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.EscapedPath()
-	slug := strings.Trim(path, "/")
+	slug := strings.Trim(strings.TrimPrefix(strings.Trim(r.URL.EscapedPath(), "/"), "nominate/"), "/")
 	topic := strings.Title(strings.Replace(slug, "-", " ", -1))
-	b, err := s.bookAPI.Search(topic, 1)
+
+	if r.Method == http.MethodPost {
+		s.submitNomination(w, r, slug)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.ProviderTimeout)
+	defer cancel()
+	b, err := s.bookAPI.Search(ctx, topic, 1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	noms, err := s.nominationStore.List(slug)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	data := map[string]interface{}{
-		"slug":  slug,
-		"topic": topic,
-		"books": b,
+		"slug":        slug,
+		"topic":       topic,
+		"books":       b,
+		"nominations": noms,
+		"csrfToken":   s.csrfToken(slug, s.ensureVisitorSecret(w, r)),
 	}
 	s.render(w, "nominate", data)
 }
 
+// submitNomination handles the POST of the nomination form for topic
+// slug: either recording a new nomination, or upvoting an existing one
+// when the submitted ISBN already has a nomination for this topic.
+func (s Site) submitNomination(w http.ResponseWriter, r *http.Request, slug string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cookie, err := r.Cookie(csrfCookieName)
+	if err != nil || !s.validCSRFToken(slug, cookie.Value, r.FormValue("csrf_token")) {
+		http.Error(w, "invalid or expired form token", http.StatusForbidden)
+		return
+	}
+
+	voter := voterID(r)
+	if !s.nominateLimiter.Allow(voter) {
+		http.Error(w, "too many nominations, please slow down", http.StatusTooManyRequests)
+		return
+	}
+
+	isbn := r.FormValue("isbn")
+	title := r.FormValue("title")
+	if isbn == "" || title == "" {
+		http.Error(w, "isbn and title are required", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := s.nominationStore.List(slug)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	alreadyNominated := false
+	for _, n := range existing {
+		if n.ISBN == isbn {
+			alreadyNominated = true
+			break
+		}
+	}
+
+	if alreadyNominated {
+		err = s.nominationStore.Upvote(slug, isbn, voter)
+	} else {
+		err = s.nominationStore.Add(nominations.Nomination{
+			Topic:       slug,
+			ISBN:        isbn,
+			Title:       title,
+			URL:         r.FormValue("url"),
+			NominatedBy: r.FormValue("nominated_by"),
+			CreatedAt:   time.Now(),
+		}, voter)
+	}
+	if err == nominations.ErrDuplicateVote {
+		http.Error(w, "you've already voted for this nomination", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/nominate/"+slug+"/", http.StatusSeeOther)
+}
+
+// csrfCookieName holds a random per-visitor secret, set on a visitor's
+// first GET of a nominate page, that binds their CSRF token so it can't
+// be reused by a different visitor (the page is otherwise public).
+const csrfCookieName = "csrf_secret"
+
+// ensureVisitorSecret returns the random secret from the visitor's
+// csrfCookieName cookie, setting one on the response first if they don't
+// already have one.
+func (s Site) ensureVisitorSecret(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(csrfCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		log.Println("ERROR:", err)
+	}
+	value := hex.EncodeToString(secret)
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    value,
+		Path:     "/nominate/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return value
+}
+
+// csrfToken returns an HMAC-SHA256 token scoped to slug and the
+// visitor's per-visitor secret (see ensureVisitorSecret), signed with
+// the Site's per-process secret. Binding the token to a secret only the
+// visitor's browser holds (via the double-submit cookie pattern)
+// prevents an attacker from fetching a valid token themselves, since
+// the nominate page is otherwise public and unauthenticated.
+func (s Site) csrfToken(slug, visitorSecret string) string {
+	mac := hmac.New(sha256.New, s.csrfSecret)
+	mac.Write([]byte(slug))
+	mac.Write([]byte{0})
+	mac.Write([]byte(visitorSecret))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s Site) validCSRFToken(slug, visitorSecret, token string) bool {
+	want := s.csrfToken(slug, visitorSecret)
+	return subtle.ConstantTimeCompare([]byte(want), []byte(token)) == 1
+}
+
+// voterID derives a stable-per-visitor identifier used for rate limiting
+// and duplicate-vote prevention, without requiring visitors to have an
+// account: a hash of their IP address.
+func voterID(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	sum := sha256.Sum256([]byte(host))
+	return hex.EncodeToString(sum[:])
+}
+
+// pruneEvery sets how many Allow calls pass between sweeps of expired
+// entries from ipRateLimiter.last, so the map doesn't grow unboundedly
+// over the life of a long-running process.
+const pruneEvery = 1000
+
+// ipRateLimiter throttles repeated actions from the same voter ID to at
+// most once per interval.
+type ipRateLimiter struct {
+	mu       sync.Mutex
+	last     map[string]time.Time
+	interval time.Duration
+	calls    int
+}
+
+func newIPRateLimiter(interval time.Duration) *ipRateLimiter {
+	return &ipRateLimiter{last: map[string]time.Time{}, interval: interval}
+}
+
+// Allow reports whether id may act now, and records that it did.
+func (l *ipRateLimiter) Allow(id string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.calls++
+	if l.calls >= pruneEvery {
+		l.calls = 0
+		l.prune(now)
+	}
+
+	if last, ok := l.last[id]; ok && now.Sub(last) < l.interval {
+		return false
+	}
+	l.last[id] = now
+	return true
+}
+
+// prune removes entries whose interval has already elapsed, so id's
+// that stop making requests don't stay in the map forever. Callers must
+// hold l.mu.
+func (l *ipRateLimiter) prune(now time.Time) {
+	for id, last := range l.last {
+		if now.Sub(last) >= l.interval {
+			delete(l.last, id)
+		}
+	}
+}
+
 // AboutHandler handles a request to the about page.
 func (s Site) AboutHandler(w http.ResponseWriter, r *http.Request) {
 	s.render(w, "about", nil)
 }
 
+// DebugCacheHandler reports the book-provider cache's hit/miss/size
+// counters as JSON, for operators diagnosing provider quota usage.
+func (s Site) DebugCacheHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.bookAPI.CacheStats()); err != nil {
+		log.Println("ERROR:", err)
+	}
+}
+
+// defaultProviders is used when BOOK_PROVIDERS is not set, preserving the
+// site's original Amazon-only behavior.
+const defaultProviders = "amazon"
+
+// providerConfig builds the cfg map passed to a provider's Factory,
+// pulling the environment variables each provider understands.
+func providerConfig(name string) map[string]string {
+	switch name {
+	case "amazon":
+		return map[string]string{
+			"AccessKey":    os.Getenv("AWS_ACCESS_KEY"),
+			"SecretKey":    os.Getenv("AWS_SECRET_KEY"),
+			"AssociateTag": envOrDefault("AMAZON_ASSOCIATE_TAG", "bbtl-20"),
+			"Host":         os.Getenv("AMAZON_HOST"),
+			"Region":       os.Getenv("AMAZON_REGION"),
+			"Marketplace":  os.Getenv("AMAZON_MARKETPLACE"),
+		}
+	case "googlebooks":
+		return map[string]string{"APIKey": os.Getenv("GOOGLE_BOOKS_API_KEY")}
+	default:
+		return nil
+	}
+}
+
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
 // main is the entrypoint for starting a new BestBookToLearn server
 func main() {
 	addr := ":8080"
 
-	awsAccessKey := os.Getenv("AWS_ACCESS_KEY")
-	awsSecretKey := os.Getenv("AWS_SECRET_KEY")
+	providerNames := strings.Split(envOrDefault("BOOK_PROVIDERS", defaultProviders), ",")
+	cfgs := map[string]map[string]string{}
+	for _, name := range providerNames {
+		cfgs[name] = providerConfig(name)
+	}
+
+	cacheTTL := books.DefaultCacheTTL
+	if raw := os.Getenv("BOOK_CACHE_TTL"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cacheTTL = d
+	}
 
-	amz := amazon.AmazonProductAPI{}
-	amz.AccessKey = awsAccessKey
-	amz.SecretKey = awsSecretKey
-	amz.Host = "webservices.amazon.com"
-	amz.AssociateTag = "bbtl-20"
-	amz.Client = &http.Client{} // optional
+	var cache books.Cache
+	if redisAddr := os.Getenv("REDIS_ADDR"); redisAddr != "" {
+		cache = rediscache.New(redis.NewClient(&redis.Options{Addr: redisAddr}), "bbtl:books:")
+	} else {
+		cache = ttlru.New(1000)
+	}
 
-	bookAPI := books.New(amz)
+	registry, err := books.NewRegistry(providerNames, cfgs, books.WithCache(cache, cacheTTL))
+	if err != nil {
+		log.Fatal(err)
+	}
+	bookAPI := books.New(registry)
+
+	var nominationStore nominations.Store
+	if dbPath := os.Getenv("NOMINATIONS_DB"); dbPath != "" {
+		nominationStore, err = sqlite.Open(dbPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		nominationStore = memory.New()
+	}
 
 	topics := []string{
 		"production-go",
@@ -235,7 +531,7 @@ func main() {
 		"discrete-mathematics",
 		"competitive-programming",
 	}
-	site, err := NewSite(topics, bookAPI, "templates", "static")
+	site, err := NewSite(topics, bookAPI, nominationStore, "templates", "static")
 	if err != nil {
 		panic(err)
 	}
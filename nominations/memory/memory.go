@@ -0,0 +1,93 @@
+// Package memory implements nominations.Store in process memory, with no
+// persistence across restarts. main.go uses it when NOMINATIONS_DB is
+// unset; it suits operators who don't need nominations to survive a
+// restart.
+package memory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/productiongo/bestbooktolearn/nominations"
+)
+
+// Store is an in-memory nominations.Store. The zero value is not usable;
+// construct one with New.
+type Store struct {
+	mu      sync.Mutex
+	byTopic map[string]map[string]*nominations.Nomination // topic -> ISBN -> nomination
+	voted   map[string]bool                               // topic + "\x00" + isbn + "\x00" + voter
+}
+
+// votedKey returns the voted map key for a topic/ISBN/voter triple.
+func votedKey(topic, isbn, voter string) string {
+	return topic + "\x00" + isbn + "\x00" + voter
+}
+
+// New returns an empty Store.
+func New() *Store {
+	return &Store{
+		byTopic: map[string]map[string]*nominations.Nomination{},
+		voted:   map[string]bool{},
+	}
+}
+
+func (s *Store) Add(n nominations.Nomination, voter string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := votedKey(n.Topic, n.ISBN, voter)
+	if s.voted[key] {
+		return nominations.ErrDuplicateVote
+	}
+
+	topic := s.byTopic[n.Topic]
+	if topic == nil {
+		topic = map[string]*nominations.Nomination{}
+		s.byTopic[n.Topic] = topic
+	}
+	if existing, ok := topic[n.ISBN]; ok {
+		existing.Votes++
+		s.voted[key] = true
+		return nil
+	}
+	n.Votes = 1
+	topic[n.ISBN] = &n
+	s.voted[key] = true
+	return nil
+}
+
+func (s *Store) List(topic string) ([]nominations.Nomination, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := []nominations.Nomination{}
+	for _, n := range s.byTopic[topic] {
+		list = append(list, *n)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Votes != list[j].Votes {
+			return list[i].Votes > list[j].Votes
+		}
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list, nil
+}
+
+func (s *Store) Upvote(topic, isbn, voter string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.byTopic[topic][isbn]
+	if !ok {
+		return nominations.ErrNotFound
+	}
+
+	key := votedKey(topic, isbn, voter)
+	if s.voted[key] {
+		return nominations.ErrDuplicateVote
+	}
+	n.Votes++
+	s.voted[key] = true
+	return nil
+}
@@ -0,0 +1,49 @@
+// Package nominations stores and ranks reader-submitted book suggestions
+// for each topic page.
+package nominations
+
+import (
+	"errors"
+	"time"
+)
+
+// Nomination is a single reader-submitted book suggestion for a topic.
+type Nomination struct {
+	Topic       string
+	ISBN        string
+	Title       string
+	URL         string
+	NominatedBy string
+	CreatedAt   time.Time
+	Votes       int
+}
+
+// ErrDuplicateVote is returned by Store.Add and Store.Upvote when voter
+// has already voted for the given topic/ISBN pair.
+var ErrDuplicateVote = errors.New("nominations: voter has already voted for this nomination")
+
+// ErrNotFound is returned by Store.Upvote when no nomination exists for
+// the given topic/ISBN pair.
+var ErrNotFound = errors.New("nominations: no such nomination")
+
+// Store persists Nominations and their votes.
+type Store interface {
+	// Add records a new nomination for Topic/ISBN with an initial vote
+	// count of one, crediting that vote to voter so a later Upvote call
+	// from the same voter is rejected as a duplicate. If a nomination
+	// already exists for that Topic/ISBN, its vote count is incremented
+	// instead. Add returns ErrDuplicateVote, and changes nothing, if voter
+	// has already voted for that Topic/ISBN.
+	Add(n Nomination, voter string) error
+
+	// List returns the nominations for topic ordered by Votes descending,
+	// ties broken by CreatedAt ascending.
+	List(topic string) ([]Nomination, error)
+
+	// Upvote adds one vote to the nomination identified by topic and
+	// isbn on behalf of voter, which should be something stable per
+	// visitor (a cookie value or a hash of their IP). It returns
+	// ErrDuplicateVote if voter has already voted for this nomination,
+	// or ErrNotFound if no such nomination exists.
+	Upvote(topic, isbn, voter string) error
+}
@@ -0,0 +1,137 @@
+// Package sqlite implements nominations.Store on top of SQLite via
+// modernc.org/sqlite, a pure-Go driver, so operators can persist
+// nominations without a cgo toolchain.
+package sqlite
+
+import (
+	"database/sql"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/productiongo/bestbooktolearn/nominations"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS nominations (
+	topic        TEXT NOT NULL,
+	isbn         TEXT NOT NULL,
+	title        TEXT NOT NULL,
+	url          TEXT NOT NULL,
+	nominated_by TEXT NOT NULL,
+	created_at   DATETIME NOT NULL,
+	votes        INTEGER NOT NULL DEFAULT 1,
+	PRIMARY KEY (topic, isbn)
+);
+
+CREATE TABLE IF NOT EXISTS nomination_votes (
+	topic TEXT NOT NULL,
+	isbn  TEXT NOT NULL,
+	voter TEXT NOT NULL,
+	PRIMARY KEY (topic, isbn, voter)
+);
+`
+
+// Store is a nominations.Store backed by a SQLite database file.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens (creating if necessary) the SQLite database at path and
+// ensures its schema exists.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database handle.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) Add(n nominations.Nomination, voter string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT OR IGNORE INTO nomination_votes (topic, isbn, voter) VALUES (?, ?, ?)`, n.Topic, n.ISBN, voter)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return nominations.ErrDuplicateVote
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO nominations (topic, isbn, title, url, nominated_by, created_at, votes)
+		VALUES (?, ?, ?, ?, ?, ?, 1)
+		ON CONFLICT (topic, isbn) DO UPDATE SET votes = votes + 1
+	`, n.Topic, n.ISBN, n.Title, n.URL, n.NominatedBy, n.CreatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) List(topic string) ([]nominations.Nomination, error) {
+	rows, err := s.db.Query(`
+		SELECT topic, isbn, title, url, nominated_by, created_at, votes
+		FROM nominations
+		WHERE topic = ?
+		ORDER BY votes DESC, created_at ASC
+	`, topic)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []nominations.Nomination{}
+	for rows.Next() {
+		var n nominations.Nomination
+		if err := rows.Scan(&n.Topic, &n.ISBN, &n.Title, &n.URL, &n.NominatedBy, &n.CreatedAt, &n.Votes); err != nil {
+			return nil, err
+		}
+		list = append(list, n)
+	}
+	return list, rows.Err()
+}
+
+func (s *Store) Upvote(topic, isbn, voter string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT OR IGNORE INTO nomination_votes (topic, isbn, voter) VALUES (?, ?, ?)`, topic, isbn, voter)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return nominations.ErrDuplicateVote
+	}
+
+	result, err := tx.Exec(`UPDATE nominations SET votes = votes + 1 WHERE topic = ? AND isbn = ?`, topic, isbn)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return nominations.ErrNotFound
+	}
+
+	return tx.Commit()
+}